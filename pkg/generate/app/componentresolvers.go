@@ -1,13 +1,31 @@
 package app
 
 import (
+	"context"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang/glog"
 
 	"k8s.io/kubernetes/pkg/util/errors"
 )
 
+const (
+	// defaultMaxEditDistance is the default Damerau-Levenshtein distance (in
+	// characters) within which a candidate is suggested by FuzzyMatchResolver.
+	defaultMaxEditDistance = 2
+	// defaultMinJaroWinkler is the default Jaro-Winkler similarity (0.0-1.0) above
+	// which a candidate is suggested by FuzzyMatchResolver.
+	defaultMinJaroWinkler = 0.9
+)
+
+// defaultSearchWorkers bounds the number of inner searchers that may run
+// concurrently when a MultiSimpleSearcher or MultiWeightedSearcher fans a
+// single Search call out across the searchers it holds.
+const defaultSearchWorkers = 5
+
 // Resolver is an interface for resolving provided input to component matches.
 // A Resolver should return ErrMultipleMatches when more than one result can
 // be constructed as a match. It should also set the score to 0.0 if this is a
@@ -20,9 +38,10 @@ type Resolver interface {
 // all results found as component matches. Notice they can even return zero or multiple
 // matches, meaning they will never return ErrNoMatch or ErrMultipleMatches and any error
 // returned is an actual error. The component match score can be used to determine how
-// precise a given match is, where 0.0 is an exact match.
+// precise a given match is, where 0.0 is an exact match. Implementations must stop their
+// work and return promptly once ctx is cancelled or its deadline is exceeded.
 type Searcher interface {
-	Search(terms ...string) (ComponentMatches, error)
+	Search(ctx context.Context, terms ...string) (ComponentMatches, error)
 }
 
 // WeightedResolver is a resolver identified as exact or not, depending on its weight
@@ -38,8 +57,14 @@ type WeightedResolver struct {
 // (no perfect match) but with only one candidate.
 type PerfectMatchWeightedResolver []WeightedResolver
 
-// Resolve resolves the provided input and returns only exact matches
+// Resolve resolves the provided input and returns only exact matches. Groups of equally
+// weighted resolvers are searched concurrently; once a group yields exactly one perfect
+// match (score 0.0), that match is returned and remaining lower-weight groups are never
+// dispatched. A group with more than one perfect match does not stop the search.
 func (r PerfectMatchWeightedResolver) Resolve(value string) (*ComponentMatch, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	imperfect := ScoredComponentMatches{}
 	var group MultiSimpleSearcher
 	var groupWeight float32 = 0.0
@@ -52,7 +77,7 @@ func (r PerfectMatchWeightedResolver) Resolve(value string) (*ComponentMatch, er
 				continue
 			}
 		}
-		matches, err := group.Search(value)
+		matches, err := group.Search(ctx, value)
 		switch {
 		case len(matches) > 0:
 			sort.Sort(ScoredComponentMatches(matches))
@@ -82,7 +107,7 @@ func (r PerfectMatchWeightedResolver) Resolve(value string) (*ComponentMatch, er
 		if isFile(value) {
 			for _, resolver := range r {
 				if _, ok := resolver.Searcher.(*TemplateFileSearcher); ok {
-					if _, err := resolver.Search(value); err != nil {
+					if _, err := resolver.Search(ctx, value); err != nil {
 						return nil, err
 					}
 				}
@@ -118,7 +143,7 @@ type FirstMatchResolver struct {
 
 // Resolve resolves as the first match returned by the Searcher
 func (r FirstMatchResolver) Resolve(value string) (*ComponentMatch, error) {
-	matches, err := r.Searcher.Search(value)
+	matches, err := r.Searcher.Search(context.Background(), value)
 	if err != nil {
 		return nil, err
 	}
@@ -138,7 +163,7 @@ type HighestScoreResolver struct {
 
 // Resolve resolves as the first highest scored match returned by the Searcher
 func (r HighestScoreResolver) Resolve(value string) (*ComponentMatch, error) {
-	matches, err := r.Searcher.Search(value)
+	matches, err := r.Searcher.Search(context.Background(), value)
 	if err != nil {
 		return nil, err
 	}
@@ -160,7 +185,7 @@ type HighestUniqueScoreResolver struct {
 // Resolve resolves as the highest scored match returned by the Searcher, and
 // guarantees the match is unique (the only match with that given score)
 func (r HighestUniqueScoreResolver) Resolve(value string) (*ComponentMatch, error) {
-	matches, err := r.Searcher.Search(value)
+	matches, err := r.Searcher.Search(context.Background(), value)
 	if err != nil {
 		return nil, err
 	}
@@ -189,7 +214,7 @@ type UniqueExactOrInexactMatchResolver struct {
 
 // Resolve resolves as the single exact or inexact match present
 func (r UniqueExactOrInexactMatchResolver) Resolve(value string) (*ComponentMatch, error) {
-	matches, err := r.Searcher.Search(value)
+	matches, err := r.Searcher.Search(context.Background(), value)
 	if err != nil {
 		return nil, err
 	}
@@ -214,48 +239,413 @@ func (r UniqueExactOrInexactMatchResolver) Resolve(value string) (*ComponentMatc
 	}
 }
 
+// SuggestingSearcher wraps a Searcher and remembers every match it has ever returned,
+// so a FuzzyMatchResolver can later suggest near misses drawn from the full candidate
+// set the searcher has observed, rather than only the (likely typo'd) value that just
+// failed to match.
+type SuggestingSearcher struct {
+	Searcher
+
+	mu         sync.Mutex
+	candidates ComponentMatches
+}
+
+// NewSuggestingSearcher wraps searcher so its results can be mined for fuzzy-match
+// candidates by a FuzzyMatchResolver.
+func NewSuggestingSearcher(searcher Searcher) *SuggestingSearcher {
+	return &SuggestingSearcher{Searcher: searcher}
+}
+
+// Search delegates to the wrapped Searcher and records every match it returns as a
+// fuzzy-match candidate for later calls to Candidates.
+func (s *SuggestingSearcher) Search(ctx context.Context, terms ...string) (ComponentMatches, error) {
+	matches, err := s.Searcher.Search(ctx, terms...)
+	if len(matches) > 0 {
+		s.mu.Lock()
+		s.candidates = append(s.candidates, matches...)
+		s.mu.Unlock()
+	}
+	return matches, err
+}
+
+// Candidates returns every match this searcher has observed so far.
+func (s *SuggestingSearcher) Candidates() ComponentMatches {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(ComponentMatches, len(s.candidates))
+	copy(out, s.candidates)
+	return out
+}
+
+// FuzzyMatchResolver wraps a Resolver and, when it reports ErrNoMatch, suggests
+// typo-tolerant matches drawn from the candidates a SuggestingSearcher has observed.
+// A candidate is suggested when it is within MaxEditDistance (Damerau-Levenshtein) or
+// at least MinJaroWinkler similar (Jaro-Winkler) to the input value. Suggestions are
+// scored as Score = 1 - similarity so they sort the same way as the inexact matches
+// ScoredComponentMatches already knows how to order.
+type FuzzyMatchResolver struct {
+	Resolver
+	Searcher *SuggestingSearcher
+
+	// MaxEditDistance is the maximum Damerau-Levenshtein distance a candidate may be
+	// from value and still be suggested. Defaults to 2 if zero.
+	MaxEditDistance int
+	// MinJaroWinkler is the minimum Jaro-Winkler similarity (0.0-1.0) a candidate may
+	// have to value and still be suggested. Defaults to 0.9 if zero.
+	MinJaroWinkler float64
+}
+
+// NewFuzzyMatchResolver wraps resolver with typo-tolerant suggestions drawn from
+// searcher's observed candidates.
+func NewFuzzyMatchResolver(resolver Resolver, searcher *SuggestingSearcher) *FuzzyMatchResolver {
+	return &FuzzyMatchResolver{Resolver: resolver, Searcher: searcher}
+}
+
+// Resolve delegates to the wrapped Resolver. If it fails with ErrNoMatch, candidates
+// gathered by Searcher are scored against value and the closest ones are attached to
+// the returned error as Suggestions.
+func (r *FuzzyMatchResolver) Resolve(value string) (*ComponentMatch, error) {
+	match, err := r.Resolver.Resolve(value)
+	noMatch, ok := err.(ErrNoMatch)
+	if !ok {
+		return match, err
+	}
+
+	maxDistance := r.MaxEditDistance
+	if maxDistance == 0 {
+		maxDistance = defaultMaxEditDistance
+	}
+	minSimilarity := r.MinJaroWinkler
+	if minSimilarity == 0 {
+		minSimilarity = defaultMinJaroWinkler
+	}
+
+	var suggestions ScoredComponentMatches
+	for _, candidate := range r.Searcher.Candidates() {
+		similarity, ok := bestSimilarity(value, candidate, maxDistance, minSimilarity)
+		if !ok {
+			continue
+		}
+		suggestion := *candidate
+		suggestion.Score = float32(1 - similarity)
+		suggestions = append(suggestions, &suggestion)
+	}
+	if len(suggestions) == 0 {
+		return nil, noMatch
+	}
+	sort.Sort(suggestions)
+	noMatch.Suggestions = suggestions
+	return nil, noMatch
+}
+
+// NewFuzzyComponentResolver builds the component resolution pipeline `oc new-app`
+// uses over searcher: exact/inexact matches via a UniqueExactOrInexactMatchResolver,
+// falling back to typo-tolerant suggestions (e.g. "did you mean nodejs?") gathered
+// from the very same searches once none match.
+func NewFuzzyComponentResolver(searcher Searcher) Resolver {
+	suggesting := NewSuggestingSearcher(searcher)
+	return NewFuzzyMatchResolver(UniqueExactOrInexactMatchResolver{Searcher: suggesting}, suggesting)
+}
+
+// bestSimilarity compares value against candidate's Name and Value, and reports the
+// highest Jaro-Winkler similarity found among the fields that also satisfy the
+// maxDistance/minSimilarity threshold. Empty fields and fields identical to value are
+// skipped. ok is false if neither field qualifies.
+func bestSimilarity(value string, candidate *ComponentMatch, maxDistance int, minSimilarity float64) (similarity float64, ok bool) {
+	for _, field := range []string{candidate.Name, candidate.Value} {
+		if field == "" || field == value {
+			continue
+		}
+		distance := damerauLevenshtein(value, field)
+		fieldSimilarity := jaroWinkler(value, field)
+		if distance > maxDistance && fieldSimilarity < minSimilarity {
+			continue
+		}
+		if fieldSimilarity > similarity {
+			similarity = fieldSimilarity
+			ok = true
+		}
+	}
+	return similarity, ok
+}
+
+// damerauLevenshtein returns the Damerau-Levenshtein edit distance (insertions,
+// deletions, substitutions, and adjacent transpositions) between a and b, compared
+// case-insensitively.
+func damerauLevenshtein(a, b string) int {
+	sa := []rune(strings.ToLower(a))
+	sb := []rune(strings.ToLower(b))
+
+	da := make(map[rune]int)
+	maxDist := len(sa) + len(sb)
+
+	d := make([][]int, len(sa)+2)
+	for i := range d {
+		d[i] = make([]int, len(sb)+2)
+	}
+	d[0][0] = maxDist
+	for i := 0; i <= len(sa); i++ {
+		d[i+1][0] = maxDist
+		d[i+1][1] = i
+	}
+	for j := 0; j <= len(sb); j++ {
+		d[0][j+1] = maxDist
+		d[1][j+1] = j
+	}
+
+	for i := 1; i <= len(sa); i++ {
+		db := 0
+		for j := 1; j <= len(sb); j++ {
+			i1 := da[sb[j-1]]
+			j1 := db
+			cost := 1
+			if sa[i-1] == sb[j-1] {
+				cost = 0
+				db = j
+			}
+			d[i+1][j+1] = min4(
+				d[i][j]+cost,
+				d[i+1][j]+1,
+				d[i][j+1]+1,
+				d[i1][j1]+(i-i1-1)+1+(j-j1-1),
+			)
+		}
+		da[sa[i-1]] = i
+	}
+	return d[len(sa)+1][len(sb)+1]
+}
+
+func min4(a, b, c, d int) int {
+	m := a
+	if b < m {
+		m = b
+	}
+	if c < m {
+		m = c
+	}
+	if d < m {
+		m = d
+	}
+	return m
+}
+
+// jaroWinkler returns the Jaro-Winkler similarity (0.0-1.0, higher is more similar)
+// between a and b, compared case-insensitively.
+func jaroWinkler(a, b string) float64 {
+	sa := []rune(strings.ToLower(a))
+	sb := []rune(strings.ToLower(b))
+	if len(sa) == 0 && len(sb) == 0 {
+		return 1.0
+	}
+	if len(sa) == 0 || len(sb) == 0 {
+		return 0.0
+	}
+
+	matchDistance := max(len(sa), len(sb))/2 - 1
+	if matchDistance < 0 {
+		matchDistance = 0
+	}
+
+	aMatches := make([]bool, len(sa))
+	bMatches := make([]bool, len(sb))
+
+	matches := 0
+	for i := range sa {
+		start := max(0, i-matchDistance)
+		end := min(len(sb), i+matchDistance+1)
+		for j := start; j < end; j++ {
+			if bMatches[j] || sa[i] != sb[j] {
+				continue
+			}
+			aMatches[i] = true
+			bMatches[j] = true
+			matches++
+			break
+		}
+	}
+	if matches == 0 {
+		return 0.0
+	}
+
+	transpositions := 0
+	k := 0
+	for i := range sa {
+		if !aMatches[i] {
+			continue
+		}
+		for !bMatches[k] {
+			k++
+		}
+		if sa[i] != sb[k] {
+			transpositions++
+		}
+		k++
+	}
+	transpositions /= 2
+
+	m := float64(matches)
+	jaro := (m/float64(len(sa)) + m/float64(len(sb)) + (m-float64(transpositions))/m) / 3.0
+
+	prefix := 0
+	for i := 0; i < min(len(sa), len(sb)) && i < 4; i++ {
+		if sa[i] != sb[i] {
+			break
+		}
+		prefix++
+	}
+
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
 // MultiSimpleSearcher is a set of searchers
 type MultiSimpleSearcher []Searcher
 
-// Search searches using all searchers it holds
-func (s MultiSimpleSearcher) Search(terms ...string) (ComponentMatches, error) {
+// simpleSearchResult carries the outcome of a single inner Searcher.Search call
+// back to the fan-in loop in MultiSimpleSearcher.Search.
+type simpleSearchResult struct {
+	matches ComponentMatches
+	err     error
+}
+
+// Search searches using all searchers it holds, running them concurrently (bounded by
+// defaultSearchWorkers) and aggregating results as they arrive. It returns early, with
+// whatever partial results have already arrived, once ctx is cancelled.
+func (s MultiSimpleSearcher) Search(ctx context.Context, terms ...string) (ComponentMatches, error) {
+	results := make(chan simpleSearchResult, len(s))
+	sem := make(chan struct{}, defaultSearchWorkers)
+	var wg sync.WaitGroup
+	for _, searcher := range s {
+		searcher := searcher
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results <- simpleSearchResult{err: ctx.Err()}
+				return
+			}
+			matches, err := searcher.Search(ctx, terms...)
+			results <- simpleSearchResult{matches: matches, err: err}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
 	var errs []error
 	componentMatches := ComponentMatches{}
-	for _, searcher := range s {
-		matches, err := searcher.Search(terms...)
-		if err != nil {
-			glog.V(2).Infof("Error occurred during search: %s", err)
-			errs = append(errs, err)
-			continue
+collect:
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				break collect
+			}
+			if result.err != nil {
+				glog.V(2).Infof("Error occurred during search: %s", result.err)
+				errs = append(errs, result.err)
+				continue
+			}
+			componentMatches = append(componentMatches, result.matches...)
+		case <-ctx.Done():
+			errs = append(errs, ctx.Err())
+			break collect
 		}
-		componentMatches = append(componentMatches, matches...)
 	}
 	sort.Sort(ScoredComponentMatches(componentMatches))
 	return componentMatches, errors.NewAggregate(errs)
 }
 
-// WeightedSearcher is a searcher identified as exact or not, depending on its weight
+// WeightedSearcher is a searcher identified as exact or not, depending on its weight.
+// Timeout, if non-zero, bounds how long this particular searcher is allowed to run
+// before its context is cancelled; a timed-out searcher simply contributes no matches.
 type WeightedSearcher struct {
 	Searcher
-	Weight float32
+	Weight  float32
+	Timeout time.Duration
 }
 
 // MultiWeightedSearcher is a set of weighted searchers where lower weight has higher
 // priority in search results
 type MultiWeightedSearcher []WeightedSearcher
 
-// Search searches using all searchers it holds and score according to searcher height
-func (s MultiWeightedSearcher) Search(terms ...string) (ComponentMatches, error) {
-	componentMatches := ComponentMatches{}
+// weightedSearchResult carries the outcome of a single inner WeightedSearcher.Search
+// call back to the fan-in loop in MultiWeightedSearcher.Search.
+type weightedSearchResult struct {
+	matches ComponentMatches
+}
+
+// Search searches using all searchers it holds concurrently (bounded by
+// defaultSearchWorkers), scoring matches according to searcher weight and honoring
+// each searcher's configured Timeout. Matches are aggregated as they arrive, and the
+// search returns early with partial results if ctx is cancelled.
+func (s MultiWeightedSearcher) Search(ctx context.Context, terms ...string) (ComponentMatches, error) {
+	results := make(chan weightedSearchResult, len(s))
+	sem := make(chan struct{}, defaultSearchWorkers)
+	var wg sync.WaitGroup
 	for _, searcher := range s {
-		matches, err := searcher.Search(terms...)
-		if err != nil {
-			glog.V(2).Infof("Error occurred during search: %#v", err)
-			continue
-		}
-		for _, match := range matches {
-			match.Score += searcher.Weight
-			componentMatches = append(componentMatches, match)
+		searcher := searcher
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			searchCtx := ctx
+			if searcher.Timeout > 0 {
+				var cancel context.CancelFunc
+				searchCtx, cancel = context.WithTimeout(ctx, searcher.Timeout)
+				defer cancel()
+			}
+
+			matches, err := searcher.Search(searchCtx, terms...)
+			if err != nil {
+				glog.V(2).Infof("Error occurred during search: %#v", err)
+				return
+			}
+			for _, match := range matches {
+				match.Score += searcher.Weight
+			}
+			results <- weightedSearchResult{matches: matches}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	componentMatches := ComponentMatches{}
+collect:
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				break collect
+			}
+			componentMatches = append(componentMatches, result.matches...)
+		case <-ctx.Done():
+			break collect
 		}
 	}
 	sort.Sort(ScoredComponentMatches(componentMatches))
@@ -263,7 +653,7 @@ func (s MultiWeightedSearcher) Search(terms ...string) (ComponentMatches, error)
 }
 
 func searchExact(searcher Searcher, value string) (exact *ComponentMatch, inexact []*ComponentMatch, err error) {
-	matches, err := searcher.Search(value)
+	matches, err := searcher.Search(context.Background(), value)
 	if err != nil {
 		return nil, nil, err
 	}
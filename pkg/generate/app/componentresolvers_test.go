@@ -0,0 +1,256 @@
+package app
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeSearcher is a Searcher whose Search behavior (returned matches/error and how long
+// it sleeps before replying) is fixed by its fields, so tests can control exactly what
+// the concurrent fan-out sees. A cancelled ctx cuts the sleep short with ctx.Err(), and
+// calls, if set, counts how many times Search was invoked.
+type fakeSearcher struct {
+	matches ComponentMatches
+	err     error
+	delay   time.Duration
+	calls   *int32
+}
+
+func (f fakeSearcher) Search(ctx context.Context, terms ...string) (ComponentMatches, error) {
+	if f.calls != nil {
+		atomic.AddInt32(f.calls, 1)
+	}
+	if f.delay > 0 {
+		select {
+		case <-time.After(f.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return f.matches, f.err
+}
+
+func TestMultiSimpleSearcher_AggregatesConcurrently(t *testing.T) {
+	a := fakeSearcher{matches: ComponentMatches{{Name: "a", Score: 0}}, delay: 50 * time.Millisecond}
+	b := fakeSearcher{matches: ComponentMatches{{Name: "b", Score: 0}}, delay: 50 * time.Millisecond}
+	c := fakeSearcher{matches: ComponentMatches{{Name: "c", Score: 0}}, delay: 50 * time.Millisecond}
+	s := MultiSimpleSearcher{a, b, c}
+
+	start := time.Now()
+	matches, err := s.Search(context.Background(), "value")
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(matches) != 3 {
+		t.Fatalf("expected 3 matches, got %d: %v", len(matches), matches)
+	}
+	// Run serially this would take >= 150ms; run concurrently it should stay well
+	// under that, proving the fan-out actually overlaps the three delays.
+	if elapsed > 130*time.Millisecond {
+		t.Errorf("searchers do not appear to have run concurrently: took %s", elapsed)
+	}
+}
+
+func TestMultiSimpleSearcher_CancelReturnsPromptly(t *testing.T) {
+	slow := fakeSearcher{matches: ComponentMatches{{Name: "slow"}}, delay: time.Hour}
+	fast := fakeSearcher{matches: ComponentMatches{{Name: "fast"}}}
+	s := MultiSimpleSearcher{slow, fast}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	done := make(chan struct{})
+	var err error
+	go func() {
+		_, err = s.Search(ctx, "value")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("MultiSimpleSearcher.Search did not return after ctx was cancelled")
+	}
+	if err == nil {
+		t.Fatal("expected an error aggregating ctx.Canceled, got nil")
+	}
+}
+
+func TestMultiWeightedSearcher_PerSearcherTimeoutDropsSlowResults(t *testing.T) {
+	slow := WeightedSearcher{
+		Searcher: fakeSearcher{matches: ComponentMatches{{Name: "slow"}}, delay: 100 * time.Millisecond},
+		Timeout:  10 * time.Millisecond,
+	}
+	fast := WeightedSearcher{
+		Searcher: fakeSearcher{matches: ComponentMatches{{Name: "fast"}}},
+		Weight:   0,
+	}
+	s := MultiWeightedSearcher{slow, fast}
+
+	matches, err := s.Search(context.Background(), "value")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Name != "fast" {
+		t.Fatalf("expected only the fast searcher's match once the slow one timed out, got %v", matches)
+	}
+}
+
+func TestMultiWeightedSearcher_PartialResultsOnCancel(t *testing.T) {
+	var calls int32
+	never := WeightedSearcher{Searcher: fakeSearcher{matches: ComponentMatches{{Name: "never"}}, delay: time.Hour, calls: &calls}}
+	s := MultiWeightedSearcher{never}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	var matches ComponentMatches
+	go func() {
+		matches, _ = s.Search(ctx, "value")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("MultiWeightedSearcher.Search did not return promptly for an already-cancelled ctx")
+	}
+	if len(matches) != 0 {
+		t.Fatalf("expected no matches from an already-cancelled search, got %v", matches)
+	}
+}
+
+func TestFuzzyMatchResolver_SuggestsWithinThreshold(t *testing.T) {
+	searcher := NewSuggestingSearcher(fakeSearcher{matches: ComponentMatches{{Name: "nodejs", Value: "nodejs"}}})
+	// Prime the searcher's observed candidates, the way a normal (successful) search
+	// for a related term would.
+	if _, err := searcher.Search(context.Background(), "node"); err != nil {
+		t.Fatalf("unexpected error priming searcher: %v", err)
+	}
+
+	resolver := NewFuzzyMatchResolver(noMatchResolver{value: "nodjes"}, searcher)
+	match, err := resolver.Resolve("nodjes")
+	if match != nil {
+		t.Fatalf("expected no confident match, got %v", match)
+	}
+	noMatch, ok := err.(ErrNoMatch)
+	if !ok {
+		t.Fatalf("expected ErrNoMatch, got %#v", err)
+	}
+	if len(noMatch.Suggestions) != 1 || noMatch.Suggestions[0].Name != "nodejs" {
+		t.Fatalf("expected a single suggestion of %q, got %v", "nodejs", noMatch.Suggestions)
+	}
+}
+
+func TestFuzzyMatchResolver_EmptyCandidateSet(t *testing.T) {
+	searcher := NewSuggestingSearcher(fakeSearcher{})
+	resolver := NewFuzzyMatchResolver(noMatchResolver{value: "nodjes"}, searcher)
+
+	match, err := resolver.Resolve("nodjes")
+	if match != nil {
+		t.Fatalf("expected no match, got %v", match)
+	}
+	noMatch, ok := err.(ErrNoMatch)
+	if !ok {
+		t.Fatalf("expected ErrNoMatch, got %#v", err)
+	}
+	if len(noMatch.Suggestions) != 0 {
+		t.Fatalf("expected no suggestions from an empty candidate set, got %v", noMatch.Suggestions)
+	}
+}
+
+func TestFuzzyMatchResolver_TiedCandidatesAreAllSuggested(t *testing.T) {
+	searcher := NewSuggestingSearcher(fakeSearcher{matches: ComponentMatches{
+		{Name: "postgres"},
+		{Name: "postgrey"},
+	}})
+	if _, err := searcher.Search(context.Background(), "seed"); err != nil {
+		t.Fatalf("unexpected error priming searcher: %v", err)
+	}
+
+	resolver := NewFuzzyMatchResolver(noMatchResolver{value: "postgre"}, searcher)
+	_, err := resolver.Resolve("postgre")
+	noMatch, ok := err.(ErrNoMatch)
+	if !ok {
+		t.Fatalf("expected ErrNoMatch, got %#v", err)
+	}
+	if len(noMatch.Suggestions) != 2 {
+		t.Fatalf("expected both equally-close candidates to be suggested, got %v", noMatch.Suggestions)
+	}
+}
+
+func TestFuzzyMatchResolver_PassesThroughNonNoMatchErrors(t *testing.T) {
+	// When the wrapped Searcher produces a tie, HighestUniqueScoreResolver reports
+	// ErrMultipleMatches rather than ErrNoMatch -- FuzzyMatchResolver must not try to
+	// paper over that with fuzzy suggestions, it should pass the error through as-is.
+	searcher := NewSuggestingSearcher(fakeSearcher{matches: ComponentMatches{
+		{Name: "nodejs", Score: 0},
+		{Name: "nodejs-lts", Score: 0},
+	}})
+	resolver := NewFuzzyMatchResolver(HighestUniqueScoreResolver{Searcher: searcher}, searcher)
+
+	match, err := resolver.Resolve("node")
+	if match != nil {
+		t.Fatalf("expected no match from a tie, got %v", match)
+	}
+	if _, ok := err.(ErrMultipleMatches); !ok {
+		t.Fatalf("expected ErrMultipleMatches to pass through unchanged, got %#v", err)
+	}
+}
+
+// termSearcher only returns matches whose Name equals one of the search terms,
+// simulating a real searcher that can tell an exact term apart from a typo'd one
+// (unlike fakeSearcher, which returns the same fixed results for every term).
+type termSearcher struct {
+	known ComponentMatches
+}
+
+func (s termSearcher) Search(ctx context.Context, terms ...string) (ComponentMatches, error) {
+	var out ComponentMatches
+	for _, term := range terms {
+		for _, m := range s.known {
+			if m.Name == term {
+				match := *m
+				out = append(out, &match)
+			}
+		}
+	}
+	return out, nil
+}
+
+func TestNewFuzzyComponentResolver_EndToEnd(t *testing.T) {
+	searcher := termSearcher{known: ComponentMatches{{Name: "nodejs", Value: "nodejs"}}}
+	resolver := NewFuzzyComponentResolver(searcher)
+
+	// Prime the shared SuggestingSearcher with a successful search.
+	if _, err := resolver.Resolve("nodejs"); err != nil {
+		t.Fatalf("unexpected error resolving an exact match: %v", err)
+	}
+
+	_, err := resolver.Resolve("nodjes")
+	noMatch, ok := err.(ErrNoMatch)
+	if !ok {
+		t.Fatalf("expected ErrNoMatch for a typo with no exact match, got %#v", err)
+	}
+	if len(noMatch.Suggestions) != 1 || noMatch.Suggestions[0].Name != "nodejs" {
+		t.Fatalf("expected the earlier successful search to seed a %q suggestion, got %v", "nodejs", noMatch.Suggestions)
+	}
+}
+
+// noMatchResolver always reports ErrNoMatch, standing in for a resolver whose
+// upstream Searcher found nothing for value.
+type noMatchResolver struct {
+	value string
+}
+
+func (r noMatchResolver) Resolve(value string) (*ComponentMatch, error) {
+	return nil, ErrNoMatch{value: r.value}
+}
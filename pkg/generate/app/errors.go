@@ -0,0 +1,21 @@
+package app
+
+import (
+	"fmt"
+)
+
+// ErrNoMatch is the error returned by a Resolver when no exact or inexact match could
+// be found for the given value.
+type ErrNoMatch struct {
+	value string
+
+	// Suggestions holds typo-tolerant near misses gathered by FuzzyMatchResolver, so
+	// callers (e.g. `oc new-app`) can print a "did you mean ...?" message instead of
+	// just reporting that nothing matched.
+	Suggestions []*ComponentMatch
+}
+
+// Error implements error
+func (e ErrNoMatch) Error() string {
+	return fmt.Sprintf("no match for %q", e.value)
+}
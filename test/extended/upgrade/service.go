@@ -0,0 +1,19 @@
+package upgrade
+
+import (
+	"k8s.io/kubernetes/test/e2e/upgrades"
+)
+
+// ServiceUpgradeTests lists the service upgrade tests run as part of a cluster
+// upgrade, covering the TCP and UDP data planes so a regression in either is caught
+// instead of only TCP.
+//
+// DualStackServiceUpgradeTest is intentionally not registered here: this vendored
+// core/v1 predates the IPFamilyPolicy/IPFamilies fields, so it can't actually request
+// a dual-stack LoadBalancer and its Setup skips on every cluster. Add it back
+// once the vendored Kubernetes API is bumped to a version that supports requesting
+// dual-stack service IP families.
+var ServiceUpgradeTests = []upgrades.Test{
+	&upgrades.ServiceUpgradeTest{},
+	&upgrades.UDPServiceUpgradeTest{},
+}
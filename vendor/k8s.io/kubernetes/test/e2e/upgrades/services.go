@@ -17,15 +17,206 @@ limitations under the License.
 package upgrades
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/kubernetes/test/e2e/framework"
 
 	"github.com/onsi/ginkgo"
 )
 
+// defaultProbeQPS is the disruption-loop probe rate used when a test's QPS field is
+// left at zero.
+const defaultProbeQPS = 10.0
+
+// probeResult is a single disruption-loop sample.
+type probeResult struct {
+	timestamp time.Time
+	success   bool
+	latency   time.Duration
+	errClass  string
+}
+
+// outageWindow describes one contiguous run of failed probes.
+type outageWindow struct {
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Duration time.Duration `json:"duration"`
+}
+
+// availabilityReport is the structured downtime/latency summary emitted at teardown,
+// suitable for tracking availability regressions across releases.
+type availabilityReport struct {
+	TotalProbes      int            `json:"totalProbes"`
+	SuccessfulProbes int            `json:"successfulProbes"`
+	Outages          []outageWindow `json:"outages"`
+	LongestOutage    time.Duration  `json:"longestOutage"`
+	P50Latency       time.Duration  `json:"p50Latency"`
+	P95Latency       time.Duration  `json:"p95Latency"`
+	P99Latency       time.Duration  `json:"p99Latency"`
+}
+
+// probeRecorder runs a fixed-rate probe loop against a probe function and records
+// every sample, so an availabilityReport can be produced at teardown. It is shared by
+// the TCP, UDP, and dual-stack service upgrade tests.
+type probeRecorder struct {
+	mu      sync.Mutex
+	results []probeResult
+}
+
+// run sends probes at qps (falling back to defaultProbeQPS if qps <= 0) until done is
+// closed, recording the outcome of each one.
+func (r *probeRecorder) run(done <-chan struct{}, qps float64, probe func() (time.Duration, string)) {
+	if qps <= 0 {
+		qps = defaultProbeQPS
+	}
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / qps))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			latency, errClass := probe()
+			r.record(probeResult{
+				timestamp: time.Now(),
+				success:   errClass == "",
+				latency:   latency,
+				errClass:  errClass,
+			})
+		}
+	}
+}
+
+func (r *probeRecorder) record(p probeResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, p)
+}
+
+// report summarizes every probe recorded so far into contiguous outage windows and
+// latency percentiles.
+func (r *probeRecorder) report() availabilityReport {
+	r.mu.Lock()
+	results := append([]probeResult(nil), r.results...)
+	r.mu.Unlock()
+
+	report := availabilityReport{TotalProbes: len(results)}
+	var latencies []time.Duration
+	var current *outageWindow
+	for _, p := range results {
+		if p.success {
+			report.SuccessfulProbes++
+			latencies = append(latencies, p.latency)
+			if current != nil {
+				report.Outages = append(report.Outages, *current)
+				current = nil
+			}
+			continue
+		}
+		if current == nil {
+			current = &outageWindow{Start: p.timestamp, End: p.timestamp}
+		} else {
+			current.End = p.timestamp
+		}
+	}
+	if current != nil {
+		report.Outages = append(report.Outages, *current)
+	}
+	for i := range report.Outages {
+		report.Outages[i].Duration = report.Outages[i].End.Sub(report.Outages[i].Start)
+		if report.Outages[i].Duration > report.LongestOutage {
+			report.LongestOutage = report.Outages[i].Duration
+		}
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	report.P50Latency = percentile(latencies, 0.50)
+	report.P95Latency = percentile(latencies, 0.95)
+	report.P99Latency = percentile(latencies, 0.99)
+	return report
+}
+
+// percentile returns the p-th percentile (0.0-1.0) of an already-sorted slice of
+// durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// logReport emits report as a structured JSON line so downstream tooling can track
+// availability regressions across releases.
+func logReport(name string, report availabilityReport) {
+	body, err := json.Marshal(report)
+	if err != nil {
+		framework.Logf("%s: failed to marshal availability report: %v", name, err)
+		return
+	}
+	framework.Logf("%s availability report: %s", name, string(body))
+}
+
+// checkMaxDowntime fails the test via framework.Failf if report's longest outage
+// window exceeds max. A zero max disables the check.
+func checkMaxDowntime(name string, report availabilityReport, max time.Duration) {
+	if max <= 0 || report.LongestOutage <= max {
+		return
+	}
+	framework.Failf("%s: longest outage %s exceeded MaxAllowedDowntime %s, outage windows: %+v", name, report.LongestOutage, max, report.Outages)
+}
+
+// probeHTTP issues a single HTTP GET against ip:port and returns the request latency
+// and an error class ("" on success, "timeout", "connection-refused", "tls-error",
+// "5xx", or "error" otherwise).
+func probeHTTP(ip string, port int, timeout time.Duration) (time.Duration, string) {
+	client := &http.Client{Timeout: timeout}
+	start := time.Now()
+	resp, err := client.Get(fmt.Sprintf("http://%s/", net.JoinHostPort(ip, strconv.Itoa(port))))
+	latency := time.Since(start)
+	if err != nil {
+		return latency, classifyHTTPError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return latency, "5xx"
+	}
+	return latency, ""
+}
+
+// classifyHTTPError buckets a probe error into one of the classes recorded in the
+// availability report.
+func classifyHTTPError(err error) string {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return "timeout"
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "connection refused"):
+		return "connection-refused"
+	case strings.Contains(msg, "tls") || strings.Contains(msg, "x509"):
+		return "tls-error"
+	default:
+		return "error"
+	}
+}
+
 // ServiceUpgradeTest tests that a service is available before and
 // after a cluster upgrade. During a master-only upgrade, it will test
 // that a service remains available during the upgrade.
@@ -34,6 +225,17 @@ type ServiceUpgradeTest struct {
 	tcpService   *v1.Service
 	tcpIngressIP string
 	svcPort      int
+
+	// QPS is the fixed rate, in probes per second, at which the disruption loop
+	// samples the service during an upgrade. Defaults to defaultProbeQPS if zero.
+	QPS float64
+
+	// MaxAllowedDowntime bounds the longest contiguous outage window tolerated during
+	// the upgrade. If exceeded, Teardown fails the test with the full outage window
+	// list instead of on the first failed probe.
+	MaxAllowedDowntime time.Duration
+
+	recorder probeRecorder
 }
 
 // Name returns the tracking name of the test.
@@ -91,18 +293,22 @@ func (t *ServiceUpgradeTest) Test(f *framework.Framework, done <-chan struct{},
 	}
 }
 
-// Teardown cleans up any remaining resources.
+// Teardown cleans up any remaining resources, after emitting a structured
+// availability report and failing the test if downtime exceeded MaxAllowedDowntime.
 func (t *ServiceUpgradeTest) Teardown(f *framework.Framework) {
+	report := t.recorder.report()
+	logReport(t.Name(), report)
+	checkMaxDowntime(t.Name(), report, t.MaxAllowedDowntime)
 	// rely on the namespace deletion to clean up everything
 }
 
 func (t *ServiceUpgradeTest) test(f *framework.Framework, done <-chan struct{}, testDuringDisruption bool) {
 	if testDuringDisruption {
 		// Continuous validation
-		ginkgo.By("continuously hitting the pod through the service's LoadBalancer")
-		wait.Until(func() {
-			t.jig.TestReachableHTTP(t.tcpIngressIP, t.svcPort, framework.LoadBalancerLagTimeoutDefault)
-		}, framework.Poll, done)
+		ginkgo.By("continuously probing the service's LoadBalancer at a fixed rate")
+		t.recorder.run(done, t.QPS, func() (time.Duration, string) {
+			return probeHTTP(t.tcpIngressIP, t.svcPort, framework.LoadBalancerLagTimeoutDefault)
+		})
 	} else {
 		// Block until upgrade is done
 		ginkgo.By("waiting for upgrade to finish without checking if service remains up")
@@ -114,3 +320,293 @@ func (t *ServiceUpgradeTest) test(f *framework.Framework, done <-chan struct{},
 	t.jig.TestReachableHTTP(t.tcpIngressIP, t.svcPort, framework.LoadBalancerLagTimeoutDefault)
 	t.jig.SanityCheckService(t.tcpService, v1.ServiceTypeLoadBalancer)
 }
+
+// udpProbeRetries is how many times a single UDP probe is retried before it is
+// counted as packet loss, since UDP delivery is not guaranteed even when the service
+// is otherwise healthy.
+const udpProbeRetries = 3
+
+// probeUDP sends a datagram to ip:port and waits for any reply, retrying up to
+// udpProbeRetries times to absorb ordinary packet loss before the probe is recorded
+// as failed. It only checks that the backing pod answers at all, not the content of
+// the reply: the pod image the jig runs is free to reply with its hostname or some
+// other fixed string rather than echoing the request verbatim, so this can't assume
+// a byte-exact echo without false-failing against that backend.
+func probeUDP(ip string, port int, timeout time.Duration) (time.Duration, string) {
+	payload := []byte("probe")
+	addr := net.JoinHostPort(ip, strconv.Itoa(port))
+
+	start := time.Now()
+	var lastErr error
+	for attempt := 0; attempt <= udpProbeRetries; attempt++ {
+		lastErr = func() error {
+			conn, err := net.DialTimeout("udp", addr, timeout)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(timeout))
+			if _, err := conn.Write(payload); err != nil {
+				return err
+			}
+			buf := make([]byte, 256)
+			if _, err := conn.Read(buf); err != nil {
+				return err
+			}
+			return nil
+		}()
+		if lastErr == nil {
+			return time.Since(start), ""
+		}
+	}
+	if netErr, ok := lastErr.(net.Error); ok && netErr.Timeout() {
+		return time.Since(start), "packet-loss"
+	}
+	return time.Since(start), "error"
+}
+
+// UDPServiceUpgradeTest tests that a UDP service is available before, during, and
+// after a cluster upgrade. It shares ServiceUpgradeTest's Setup/Test/Teardown
+// structure, but probes over UDP with retry/loss accounting instead of treating
+// every dropped datagram as a hard failure.
+type UDPServiceUpgradeTest struct {
+	jig          *framework.ServiceTestJig
+	udpService   *v1.Service
+	udpIngressIP string
+	svcPort      int
+
+	// QPS is the fixed rate, in probes per second, at which the disruption loop
+	// samples the service during an upgrade. Defaults to defaultProbeQPS if zero.
+	QPS float64
+
+	// MaxAllowedDowntime bounds the longest contiguous outage window tolerated during
+	// the upgrade. If exceeded, Teardown fails the test with the full outage window
+	// list instead of on the first failed probe.
+	MaxAllowedDowntime time.Duration
+
+	recorder probeRecorder
+}
+
+// Name returns the tracking name of the test.
+func (UDPServiceUpgradeTest) Name() string { return "udp-service-upgrade" }
+
+// Setup creates a UDP service with a load balancer and makes sure it's reachable.
+func (t *UDPServiceUpgradeTest) Setup(f *framework.Framework) {
+	serviceName := "udp-service-test"
+	jig := framework.NewServiceTestJig(f.ClientSet, serviceName)
+
+	ns := f.Namespace
+
+	ginkgo.By("creating a UDP service " + serviceName + " with type=LoadBalancer in namespace " + ns.Name)
+	udpService := jig.CreateUDPServiceOrFail(ns.Name, func(s *v1.Service) {
+		s.Spec.Type = v1.ServiceTypeLoadBalancer
+	})
+	udpService = jig.WaitForLoadBalancerOrFail(ns.Name, udpService.Name, framework.LoadBalancerCreateTimeoutDefault)
+	jig.SanityCheckService(udpService, v1.ServiceTypeLoadBalancer)
+
+	udpIngressIP := framework.GetIngressPoint(&udpService.Status.LoadBalancer.Ingress[0])
+	svcPort := int(udpService.Spec.Ports[0].Port)
+
+	ginkgo.By("creating pod to be part of service " + serviceName)
+	rc := jig.RunOrFail(ns.Name, jig.AddRCAntiAffinity)
+
+	if shouldTestPDBs() {
+		ginkgo.By("creating a PodDisruptionBudget to cover the ReplicationController")
+		jig.CreatePDBOrFail(ns.Name, rc)
+	}
+
+	ginkgo.By("hitting the pod through the service's LoadBalancer")
+	if _, errClass := probeUDP(udpIngressIP, svcPort, 3*time.Minute); errClass != "" {
+		framework.Failf("UDP service %s not reachable: %s", serviceName, errClass)
+	}
+
+	t.jig = jig
+	t.udpService = udpService
+	t.udpIngressIP = udpIngressIP
+	t.svcPort = svcPort
+}
+
+// Test runs a connectivity check to the service.
+func (t *UDPServiceUpgradeTest) Test(f *framework.Framework, done <-chan struct{}, upgrade UpgradeType) {
+	switch upgrade {
+	case MasterUpgrade, ClusterUpgrade:
+		t.test(f, done, true)
+	case NodeUpgrade:
+		// Node upgrades should test during disruption only on GCE/GKE for now.
+		t.test(f, done, shouldTestPDBs())
+	default:
+		t.test(f, done, false)
+	}
+}
+
+// Teardown cleans up any remaining resources, after emitting a structured
+// availability report and failing the test if downtime exceeded MaxAllowedDowntime.
+func (t *UDPServiceUpgradeTest) Teardown(f *framework.Framework) {
+	report := t.recorder.report()
+	logReport(t.Name(), report)
+	checkMaxDowntime(t.Name(), report, t.MaxAllowedDowntime)
+	// rely on the namespace deletion to clean up everything
+}
+
+func (t *UDPServiceUpgradeTest) test(f *framework.Framework, done <-chan struct{}, testDuringDisruption bool) {
+	if testDuringDisruption {
+		ginkgo.By("continuously probing the UDP service's LoadBalancer at a fixed rate")
+		t.recorder.run(done, t.QPS, func() (time.Duration, string) {
+			return probeUDP(t.udpIngressIP, t.svcPort, framework.LoadBalancerLagTimeoutDefault)
+		})
+	} else {
+		ginkgo.By("waiting for upgrade to finish without checking if service remains up")
+		<-done
+	}
+
+	ginkgo.By("hitting the pod through the service's LoadBalancer")
+	if _, errClass := probeUDP(t.udpIngressIP, t.svcPort, framework.LoadBalancerLagTimeoutDefault); errClass != "" {
+		framework.Failf("UDP service %s not reachable after upgrade: %s", t.Name(), errClass)
+	}
+	t.jig.SanityCheckService(t.udpService, v1.ServiceTypeLoadBalancer)
+}
+
+// DualStackServiceUpgradeTest tests that a dual-stack service remains reachable over
+// both its IPv4 and IPv6 ingress addresses across a cluster upgrade. It shares
+// ServiceUpgradeTest's Setup/Test/Teardown structure, but probes both address
+// families concurrently and fails if either one drops.
+//
+// This vendored core/v1 predates the IPFamilyPolicy/IPFamilies fields needed to
+// actually request a dual-stack LoadBalancer, so Setup cannot provision one and
+// skips instead of running. It is NOT registered in the cluster upgrade test suite
+// (test/extended/upgrade) for that reason; UDPServiceUpgradeTest is registered
+// there alongside ServiceUpgradeTest. Re-enable both once the vendored Kubernetes
+// API is bumped to a version that supports requesting dual-stack service IP
+// families.
+type DualStackServiceUpgradeTest struct {
+	jig         *framework.ServiceTestJig
+	service     *v1.Service
+	v4IngressIP string
+	v6IngressIP string
+	svcPort     int
+
+	// QPS is the fixed rate, in probes per second, at which each address family is
+	// sampled during an upgrade. Defaults to defaultProbeQPS if zero.
+	QPS float64
+
+	// MaxAllowedDowntime bounds the longest contiguous outage window tolerated during
+	// the upgrade, checked independently for each address family.
+	MaxAllowedDowntime time.Duration
+
+	v4Recorder probeRecorder
+	v6Recorder probeRecorder
+}
+
+// Name returns the tracking name of the test.
+func (DualStackServiceUpgradeTest) Name() string { return "dual-stack-service-upgrade" }
+
+// Setup creates a dual-stack service with a load balancer and makes sure it's
+// reachable over both address families.
+//
+// This vendored core/v1 predates the IPFamilyPolicy/IPFamilies fields that upstream
+// later added for requesting dual-stack explicitly, and there is no other way in
+// this API to ask a LoadBalancer service for both families: a plain
+// CreateTCPServiceOrFail request is fulfilled single-family by every cluster, so
+// the test skips rather than running a check that would fail unconditionally.
+func (t *DualStackServiceUpgradeTest) Setup(f *framework.Framework) {
+	framework.Skipf("dual-stack service upgrade test requires IPFamilyPolicy, which this vendored core/v1 does not support")
+
+	serviceName := "dual-stack-service-test"
+	jig := framework.NewServiceTestJig(f.ClientSet, serviceName)
+
+	ns := f.Namespace
+
+	ginkgo.By("creating a dual-stack TCP service " + serviceName + " with type=LoadBalancer in namespace " + ns.Name)
+	service := jig.CreateTCPServiceOrFail(ns.Name, func(s *v1.Service) {
+		s.Spec.Type = v1.ServiceTypeLoadBalancer
+	})
+	service = jig.WaitForLoadBalancerOrFail(ns.Name, service.Name, framework.LoadBalancerCreateTimeoutDefault)
+	jig.SanityCheckService(service, v1.ServiceTypeLoadBalancer)
+
+	var v4IngressIP, v6IngressIP string
+	for i := range service.Status.LoadBalancer.Ingress {
+		ip := framework.GetIngressPoint(&service.Status.LoadBalancer.Ingress[i])
+		if strings.Contains(ip, ":") {
+			v6IngressIP = ip
+		} else {
+			v4IngressIP = ip
+		}
+	}
+	if v4IngressIP == "" || v6IngressIP == "" {
+		framework.Failf("expected both an IPv4 and an IPv6 LoadBalancer ingress address for dual-stack service %s, got v4=%q v6=%q", serviceName, v4IngressIP, v6IngressIP)
+	}
+	svcPort := int(service.Spec.Ports[0].Port)
+
+	ginkgo.By("creating pod to be part of service " + serviceName)
+	rc := jig.RunOrFail(ns.Name, jig.AddRCAntiAffinity)
+
+	if shouldTestPDBs() {
+		ginkgo.By("creating a PodDisruptionBudget to cover the ReplicationController")
+		jig.CreatePDBOrFail(ns.Name, rc)
+	}
+
+	ginkgo.By("hitting the pod through both the IPv4 and IPv6 LoadBalancer addresses")
+	jig.TestReachableHTTP(v4IngressIP, svcPort, 3*time.Minute)
+	jig.TestReachableHTTP(v6IngressIP, svcPort, 3*time.Minute)
+
+	t.jig = jig
+	t.service = service
+	t.v4IngressIP = v4IngressIP
+	t.v6IngressIP = v6IngressIP
+	t.svcPort = svcPort
+}
+
+// Test runs a connectivity check to the service over both address families.
+func (t *DualStackServiceUpgradeTest) Test(f *framework.Framework, done <-chan struct{}, upgrade UpgradeType) {
+	switch upgrade {
+	case MasterUpgrade, ClusterUpgrade:
+		t.test(f, done, true)
+	case NodeUpgrade:
+		// Node upgrades should test during disruption only on GCE/GKE for now.
+		t.test(f, done, shouldTestPDBs())
+	default:
+		t.test(f, done, false)
+	}
+}
+
+// Teardown cleans up any remaining resources, after emitting a structured
+// availability report for each address family and failing the test if either
+// family's downtime exceeded MaxAllowedDowntime.
+func (t *DualStackServiceUpgradeTest) Teardown(f *framework.Framework) {
+	v4Report := t.v4Recorder.report()
+	v6Report := t.v6Recorder.report()
+	logReport(t.Name()+"-ipv4", v4Report)
+	logReport(t.Name()+"-ipv6", v6Report)
+	checkMaxDowntime(t.Name()+"-ipv4", v4Report, t.MaxAllowedDowntime)
+	checkMaxDowntime(t.Name()+"-ipv6", v6Report, t.MaxAllowedDowntime)
+	// rely on the namespace deletion to clean up everything
+}
+
+func (t *DualStackServiceUpgradeTest) test(f *framework.Framework, done <-chan struct{}, testDuringDisruption bool) {
+	if testDuringDisruption {
+		ginkgo.By("continuously probing both the IPv4 and IPv6 LoadBalancer addresses at a fixed rate")
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			t.v4Recorder.run(done, t.QPS, func() (time.Duration, string) {
+				return probeHTTP(t.v4IngressIP, t.svcPort, framework.LoadBalancerLagTimeoutDefault)
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			t.v6Recorder.run(done, t.QPS, func() (time.Duration, string) {
+				return probeHTTP(t.v6IngressIP, t.svcPort, framework.LoadBalancerLagTimeoutDefault)
+			})
+		}()
+		wg.Wait()
+	} else {
+		ginkgo.By("waiting for upgrade to finish without checking if service remains up")
+		<-done
+	}
+
+	ginkgo.By("hitting the pod through both the IPv4 and IPv6 LoadBalancer addresses")
+	t.jig.TestReachableHTTP(t.v4IngressIP, t.svcPort, framework.LoadBalancerLagTimeoutDefault)
+	t.jig.TestReachableHTTP(t.v6IngressIP, t.svcPort, framework.LoadBalancerLagTimeoutDefault)
+	t.jig.SanityCheckService(t.service, v1.ServiceTypeLoadBalancer)
+}